@@ -75,7 +75,7 @@ func (r *PodNotifRestart) ValidateDelete() (admission.Warnings, error) {
 
 func (r *PodNotifRestart) ValidateWebhook() error {
 	if !r.Validate() {
-		return fmt.Errorf("at least one webhook URL should be specified")
+		return fmt.Errorf("at least one notifier (spec.notifiers or spec.notifyURLs) should be specified")
 	}
 
 	return nil
@@ -0,0 +1,206 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	// to ensure that exec-entrypoint and run can make use of them.
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	monitoringv1 "github.com/NoNickeD/pod-restart-notifier-operator-crd/api/v1"
+	controllers "github.com/NoNickeD/pod-restart-notifier-operator-crd/internal/controller"
+	"github.com/NoNickeD/pod-restart-notifier-operator-crd/pkg/alertmanager"
+	webhookcerts "github.com/NoNickeD/pod-restart-notifier-operator-crd/pkg/webhook"
+)
+
+var (
+	scheme   = runtime.NewScheme()
+	setupLog = ctrl.Log.WithName("setup")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(monitoringv1.AddToScheme(scheme))
+}
+
+func main() {
+	var metricsAddr string
+	var probeAddr string
+	var alertmanagerAddr string
+	var enableLeaderElection bool
+	var enableWebhooks bool
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&alertmanagerAddr, "alertmanager-bind-address", ":9094", "The address the Alertmanager webhook receiver binds to.")
+	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
+		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", true, "Enable the validating/defaulting admission webhooks.")
+	opts := zap.Options{Development: true}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
+		HealthProbeBindAddress: probeAddr,
+		LeaderElection:         enableLeaderElection,
+		LeaderElectionID:       "pod-restart-notifier-operator-crd.nonnicked.io",
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to build kubernetes clientset")
+		os.Exit(1)
+	}
+
+	reconciler := &controllers.PodNotifRestartReconciler{
+		Client:    mgr.GetClient(),
+		Log:       ctrl.Log.WithName("controllers").WithName("PodNotifRestart"),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("podnotifrestart-controller"),
+		Clientset: clientset,
+	}
+	if err = reconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PodNotifRestart")
+		os.Exit(1)
+	}
+
+	if enableWebhooks {
+		if err = mgr.Add(newCertRunnable(mgr, webhookcerts.Options{
+			ServiceName:           "pod-restart-notifier-operator-crd-webhook-service",
+			ServiceNamespace:      os.Getenv("POD_NAMESPACE"),
+			SecretName:            "pod-restart-notifier-operator-crd-webhook-server-cert",
+			ValidatingWebhookName: "pod-restart-notifier-operator-crd-validating-webhook-configuration",
+			MutatingWebhookName:   "pod-restart-notifier-operator-crd-mutating-webhook-configuration",
+		})); err != nil {
+			setupLog.Error(err, "unable to register webhook cert provisioner")
+			os.Exit(1)
+		}
+		if err = (&monitoringv1.PodNotifRestart{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PodNotifRestart")
+			os.Exit(1)
+		}
+	}
+
+	// The Alertmanager webhook receiver runs alongside the manager so
+	// KubePodCrashLooping-style alerting rules can drive the same
+	// notifier fan-out as the watch-based reconciler.
+	if err = mgr.Add(newAlertmanagerRunnable(alertmanagerAddr, mgr)); err != nil {
+		setupLog.Error(err, "unable to register alertmanager webhook receiver")
+		os.Exit(1)
+	}
+
+	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err = mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+
+	setupLog.Info("starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
+
+// alertmanagerRunnable starts and stops the Alertmanager webhook receiver
+// alongside the manager, so it only serves while this instance holds
+// leadership.
+type alertmanagerRunnable struct {
+	addr   string
+	mgr    ctrl.Manager
+	server *http.Server
+}
+
+func newAlertmanagerRunnable(addr string, mgr ctrl.Manager) *alertmanagerRunnable {
+	return &alertmanagerRunnable{addr: addr, mgr: mgr}
+}
+
+func (a *alertmanagerRunnable) Start(ctx context.Context) error {
+	handler := &alertmanager.Handler{
+		Client: a.mgr.GetClient(),
+		Log:    ctrl.Log.WithName("alertmanager-webhook"),
+	}
+
+	a.server = &http.Server{Addr: a.addr, Handler: handler}
+	errCh := make(chan error, 1)
+	go func() {
+		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return a.server.Shutdown(context.Background())
+	case err := <-errCh:
+		return err
+	}
+}
+
+// NeedLeaderElection marks alertmanagerRunnable as a manager.LeaderElectionRunnable,
+// so the manager only starts it on the instance that holds leadership, matching
+// the rest of this operator's controllers.
+func (a *alertmanagerRunnable) NeedLeaderElection() bool {
+	return true
+}
+
+// certRunnable provisions the webhook serving certs as a leader-election-only
+// manager.Runnable, so only one replica writes the shared Secret and patches
+// the cluster-scoped webhook configurations instead of every replica racing
+// to do so on each startup.
+type certRunnable struct {
+	mgr  ctrl.Manager
+	opts webhookcerts.Options
+}
+
+func newCertRunnable(mgr ctrl.Manager, opts webhookcerts.Options) *certRunnable {
+	return &certRunnable{mgr: mgr, opts: opts}
+}
+
+func (c *certRunnable) Start(ctx context.Context) error {
+	return webhookcerts.EnsureServingCerts(ctx, c.mgr.GetClient(), c.opts)
+}
+
+// NeedLeaderElection marks certRunnable as a manager.LeaderElectionRunnable,
+// matching alertmanagerRunnable.
+func (c *certRunnable) NeedLeaderElection() bool {
+	return true
+}
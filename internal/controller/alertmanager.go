@@ -0,0 +1,68 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	monitoringv1 "github.com/NoNickeD/pod-restart-notifier-operator-crd/api/v1"
+)
+
+// NotificationTarget pairs a PodNotifRestart's Spec with the notifiers bound
+// from it, preserving the per-CR Spec that renderAndSend needs for template
+// resolution.
+type NotificationTarget struct {
+	Spec      monitoringv1.PodNotifRestartSpec
+	Notifiers []BoundNotifier
+}
+
+// NotificationTargetsForNamespace returns one NotificationTarget per
+// PodNotifRestart whose NamespacesToMonitor covers ns, so out-of-band
+// notification sources (e.g. the Alertmanager webhook receiver) can reuse
+// the exact same notifier configuration and template resolution as the
+// watch-based reconciler instead of duplicating it.
+func NotificationTargetsForNamespace(ctx context.Context, c client.Client, ns string) ([]NotificationTarget, error) {
+	var list monitoringv1.PodNotifRestartList
+	if err := c.List(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	var targets []NotificationTarget
+	for i := range list.Items {
+		pnr := &list.Items[i]
+		if !namespaceMonitored(pnr.GetNamespacesToMonitor(), ns) {
+			continue
+		}
+
+		notifiers, err := BuildNotifiers(ctx, c, pnr.Namespace, pnr.Spec)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, NotificationTarget{Spec: pnr.Spec, Notifiers: notifiers})
+	}
+	return targets, nil
+}
+
+// SendAlertNotification renders data through target's resolved per-sink
+// templates and delivers it via renderAndSend, so Alertmanager-triggered
+// notifications record pnr_notifications_sent_total and
+// pnr_notifier_send_duration_seconds exactly like pod-restart notifications.
+func SendAlertNotification(data TemplateData, target NotificationTarget) error {
+	return renderAndSend(data, target.Spec, target.Notifiers)
+}
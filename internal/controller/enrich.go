@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// recentLogLines is how many trailing lines of a crashed container's
+// previous run are fetched for template enrichment.
+const recentLogLines = 20
+
+// recentWarningLimit caps how many recent Warning event reasons are
+// surfaced to a template.
+const recentWarningLimit = 5
+
+// buildTemplateData enriches a container restart into the full TemplateData
+// a message template can render: the pod/container/restart count, the
+// container's last terminated state, its node, its resolved owning
+// controller, recent log lines, and recent Warning events (e.g. BackOff,
+// OOMKilled).
+func (r *PodNotifRestartReconciler) buildTemplateData(ctx context.Context, pod *corev1.Pod, status corev1.ContainerStatus) TemplateData {
+	data := TemplateData{
+		Pod:          *pod,
+		Container:    status.Name,
+		RestartCount: status.RestartCount,
+		Node:         pod.Spec.NodeName,
+	}
+
+	if status.LastTerminationState.Terminated != nil {
+		t := status.LastTerminationState.Terminated
+		data.LastState = LastContainerState{
+			ExitCode:   t.ExitCode,
+			Reason:     t.Reason,
+			Signal:     t.Signal,
+			FinishedAt: t.FinishedAt,
+		}
+	}
+
+	data.Owner = r.resolveOwner(ctx, pod)
+	data.RecentLogs = r.fetchRecentLogs(ctx, pod, status.Name)
+	data.Warnings = r.fetchRecentWarnings(ctx, pod)
+
+	return data
+}
+
+// resolveOwner walks OwnerReferences from pod up through a ReplicaSet to the
+// Deployment that manages it, or returns the pod's direct controller (e.g. a
+// StatefulSet or DaemonSet) when there's no ReplicaSet hop.
+func (r *PodNotifRestartReconciler) resolveOwner(ctx context.Context, pod *corev1.Pod) OwnerInfo {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return OwnerInfo{}
+	}
+
+	if ref.Kind != "ReplicaSet" {
+		return OwnerInfo{Kind: ref.Kind, Name: ref.Name}
+	}
+
+	var rs appsv1.ReplicaSet
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: ref.Name}, &rs); err != nil {
+		return OwnerInfo{Kind: ref.Kind, Name: ref.Name}
+	}
+
+	if rsOwner := metav1.GetControllerOf(&rs); rsOwner != nil {
+		return OwnerInfo{Kind: rsOwner.Kind, Name: rsOwner.Name}
+	}
+	return OwnerInfo{Kind: ref.Kind, Name: ref.Name}
+}
+
+// fetchRecentLogs returns the trailing recentLogLines lines of container's
+// previous run in pod, or nil when Clientset isn't configured or the logs
+// can't be fetched (e.g. there is no previous run yet).
+func (r *PodNotifRestartReconciler) fetchRecentLogs(ctx context.Context, pod *corev1.Pod, container string) []string {
+	if r.Clientset == nil {
+		return nil
+	}
+
+	tailLines := int64(recentLogLines)
+	req := r.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		Previous:  true,
+		TailLines: &tailLines,
+	})
+
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return nil
+	}
+	defer stream.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// fetchRecentWarnings returns the Reason of pod's most recent Warning
+// events, oldest first, capped to recentWarningLimit.
+func (r *PodNotifRestartReconciler) fetchRecentWarnings(ctx context.Context, pod *corev1.Pod) []string {
+	var events corev1.EventList
+	if err := r.Client.List(ctx, &events,
+		client.InNamespace(pod.Namespace),
+		client.MatchingFields{eventInvolvedObjectUIDField: string(pod.UID)},
+	); err != nil {
+		r.Log.Error(err, "unable to list events for pod", "pod", pod.Name)
+		return nil
+	}
+
+	items := events.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		ti, tj := items[i].LastTimestamp.Time, items[j].LastTimestamp.Time
+		if ti.IsZero() {
+			ti = items[i].EventTime.Time
+		}
+		if tj.IsZero() {
+			tj = items[j].EventTime.Time
+		}
+		return ti.Before(tj)
+	})
+
+	var warnings []string
+	for _, e := range items {
+		if e.Type != corev1.EventTypeWarning {
+			continue
+		}
+		warnings = append(warnings, e.Reason)
+	}
+
+	if len(warnings) > recentWarningLimit {
+		warnings = warnings[len(warnings)-recentWarningLimit:]
+	}
+	return warnings
+}
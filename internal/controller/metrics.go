@@ -0,0 +1,61 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// notificationsSentTotal counts notifications sent per provider, split by
+	// whether the send succeeded.
+	notificationsSentTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pnr_notifications_sent_total",
+		Help: "Total number of restart notifications sent, by provider and result.",
+	}, []string{"provider", "result"})
+
+	// podRestartsObservedTotal counts container restarts that crossed
+	// MinRestarts and triggered notifier fan-out.
+	podRestartsObservedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pnr_pod_restarts_observed_total",
+		Help: "Total number of container restarts observed that triggered a notification.",
+	}, []string{"namespace", "pod", "container"})
+
+	// notifierSendDuration observes how long each notifier's Notify/NotifyRich
+	// call takes, by provider.
+	notifierSendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pnr_notifier_send_duration_seconds",
+		Help:    "Time spent delivering a notification to a sink, by provider.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	// reconcileErrorsTotal counts Reconcile calls that returned an error.
+	reconcileErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "pnr_reconcile_errors_total",
+		Help: "Total number of PodNotifRestart reconcile errors.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		notificationsSentTotal,
+		podRestartsObservedTotal,
+		notifierSendDuration,
+		reconcileErrorsTotal,
+	)
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseShoutrrrURL(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "slack",
+			raw:  "slack://T000:B000:XXXX@general",
+			want: "https://hooks.slack.com/services/T000/B000/XXXX",
+		},
+		{
+			name: "discord",
+			raw:  "discord://token@123456",
+			want: "https://discord.com/api/webhooks/123456/token",
+		},
+		{
+			name: "teams",
+			raw:  "teams://outlook.office.com/webhook/IncomingWebhook/abc",
+			want: "https://outlook.office.com/webhook/IncomingWebhook/abc",
+		},
+		{
+			name:    "unsupported scheme",
+			raw:     "xmpp://user@host",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bound, err := ParseShoutrrrURL(tc.raw, nil)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %q, got none", tc.raw)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %v", tc.raw, err)
+			}
+
+			var got string
+			switch n := bound.Notifier.(type) {
+			case *SlackNotifier:
+				got = n.WebhookURL
+			case *DiscordNotifier:
+				got = n.WebhookURL
+			case *TeamsNotifier:
+				got = n.WebhookURL
+			default:
+				t.Fatalf("unexpected notifier type %T", n)
+			}
+
+			if got != tc.want {
+				t.Errorf("webhook URL = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJSONPayloadEscapesControlCharacters(t *testing.T) {
+	// Raw container log lines can carry bytes (e.g. the ANSI color escape
+	// \x1b) that Go's %q string-quoting would render as \xNN, which is not
+	// a legal JSON escape sequence.
+	message := "crash loop \x1b[31mFATAL\x1b[0m"
+
+	payload, err := jsonPayload(map[string]string{"text": message})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded map[string]string
+	if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+		t.Fatalf("payload is not valid JSON: %v\npayload: %s", err, payload)
+	}
+	if decoded["text"] != message {
+		t.Errorf("decoded text = %q, want %q", decoded["text"], message)
+	}
+}
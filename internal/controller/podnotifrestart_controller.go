@@ -1,65 +1,31 @@
 package controllers
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"net/http"
-	"os"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	monitoringv1 "github.com/NoNickeD/pod-restart-notifier-operator-crd/api/v1"
-
-	corev1 "k8s.io/api/core/v1"
 )
 
-// Notifier interface
-type Notifier interface {
-	Notify(message string) error
-}
-
-// DiscordNotifier struct
-type DiscordNotifier struct {
-	WebhookURL string
-}
-
-func (d *DiscordNotifier) Notify(message string) error {
-	payload := fmt.Sprintf(`{"content": "%s"}`, message)
-	return postMessage(d.WebhookURL, payload)
-}
-
-// TeamsNotifier struct
-type TeamsNotifier struct {
-	WebhookURL string
-}
-
-func (t *TeamsNotifier) Notify(message string) error {
-	payload := fmt.Sprintf(`{
-		"@type": "MessageCard",
-		"@context": "http://schema.org/extensions",
-		"summary": "Pod Restart Notification",
-		"themeColor": "0078D7",
-		"text": "%s"
-	}`, message)
-	return postMessage(t.WebhookURL, payload)
-}
-
-// SlackNotifier struct
-type SlackNotifier struct {
-	WebhookURL string
-}
-
-func (s *SlackNotifier) Notify(message string) error {
-	payload := fmt.Sprintf(`{"text": "%s"}`, message)
-	return postMessage(s.WebhookURL, payload)
-}
+// eventInvolvedObjectUIDField is the field index registered on Event so
+// recent Warning events for a pod can be looked up by involvedObject.uid.
+const eventInvolvedObjectUIDField = "involvedObject.uid"
 
 // PodNotifRestartReconciler struct
 type PodNotifRestartReconciler struct {
@@ -67,6 +33,21 @@ type PodNotifRestartReconciler struct {
 	Log      logr.Logger
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+
+	// Clientset is used to fetch container logs via the pods/log
+	// subresource, which the controller-runtime client doesn't expose. Log
+	// enrichment is skipped when nil.
+	Clientset kubernetes.Interface
+
+	// restartCountsMu guards restartCounts, the in-memory last-seen
+	// RestartCount per pod/container, keyed by the owning PodNotifRestart's
+	// NamespacedName. It is the fast path for detecting deltas;
+	// Status.LastRestartCounts mirrors it for visibility and to survive
+	// operator restarts.
+	restartCountsMu sync.Mutex
+	restartCounts   map[types.NamespacedName]map[string]int32
+
+	rateLimiters rateLimiterStore
 }
 
 // Reconcile function
@@ -77,90 +58,285 @@ func (r *PodNotifRestartReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	if err := r.Client.Get(ctx, req.NamespacedName, &pnr); err != nil {
 		if errors.IsNotFound(err) {
 			// If the resource is not found, it might have been deleted
+			r.forgetResource(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "unable to fetch PodNotifRestart")
+		reconcileErrorsTotal.Inc()
 		return ctrl.Result{}, err
 	}
 
-	var podList corev1.PodList
-	if err := r.Client.List(ctx, &podList, client.InNamespace(pnr.Namespace)); err != nil {
+	pods, err := r.listMonitoredPods(ctx, &pnr)
+	if err != nil {
 		log.Error(err, "unable to list pods")
+		reconcileErrorsTotal.Inc()
 		return ctrl.Result{}, err
 	}
 
-	// Initialize notifiers
-	discord := &DiscordNotifier{WebhookURL: os.Getenv("DISCORD_WEBHOOK_URL")}
-	teams := &TeamsNotifier{WebhookURL: os.Getenv("TEAMS_WEBHOOK_URL")}
-	slack := &SlackNotifier{WebhookURL: os.Getenv("SLACK_WEBHOOK_URL")}
+	// Build the notifier fan-out set from the CR's Notifiers/NotifyURLs,
+	// resolving any SecretKeyRefs against the cluster.
+	notifiers, err := BuildNotifiers(ctx, r.Client, pnr.Namespace, pnr.Spec)
+	if err != nil {
+		log.Error(err, "unable to build notifiers")
+		reconcileErrorsTotal.Inc()
+		return ctrl.Result{}, err
+	}
 
-	for _, pod := range podList.Items {
-		for _, status := range pod.Status.ContainerStatuses {
-			if status.RestartCount >= pnr.Spec.MinRestarts {
-				message := fmt.Sprintf("Pod %s has restarted %d times", pod.Name, status.RestartCount)
+	// detectRestarts only advances last-seen state for containers that
+	// aren't pending notification; entries in restarted are committed
+	// individually below, once their notification actually succeeds, so a
+	// failed send is retried on the next reconcile instead of silently
+	// dropped.
+	restarted := r.detectRestarts(req.NamespacedName, &pnr, pods)
+	if len(restarted) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	if dedupWindow := pnr.Spec.DedupWindow.Duration; dedupWindow > 0 {
+		if last := pnr.Status.LastNotificationTime.Time; !last.IsZero() && time.Since(last) < dedupWindow {
+			log.Info("suppressing notification, within dedup window", "dedupWindow", dedupWindow)
+			return ctrl.Result{}, nil
+		}
+	}
+
+	var sendErr error
+	notifiedAny := false
+	for _, restart := range restarted {
+		// Checked per restart, not once per reconcile, so a burst spanning
+		// many pods/containers in a single reconcile is still throttled to
+		// Spec.NotificationRateLimit instead of the first Allow() call
+		// gating an unbounded number of sends.
+		if limiter := r.rateLimiters.get(req.NamespacedName, pnr.Spec.NotificationRateLimit); limiter != nil && !limiter.Allow() {
+			log.Info("suppressing notification, rate limit exceeded", "pod", restart.pod.Name, "container", restart.status.Name)
+			continue
+		}
+
+		data := r.buildTemplateData(ctx, &restart.pod, restart.status)
+
+		// Adding log line to output restart information
+		log.Info("Sending restart notification", "pod", restart.pod.Name, "restartCount", restart.status.RestartCount)
+
+		if err := renderAndSend(data, pnr.Spec, notifiers); err != nil {
+			log.Error(err, "failed to send notification", "pod", restart.pod.Name, "container", restart.status.Name)
+			sendErr = err
+			continue
+		}
+
+		r.commitRestart(req.NamespacedName, &pnr, restart)
+		pnr.AddNotificationSent()
+		notifiedAny = true
+	}
+
+	if notifiedAny {
+		pnr.SetLastNotificationTime(metav1.Now())
+	}
+	if err := r.Status().Update(ctx, &pnr); err != nil {
+		log.Error(err, "unable to update PodNotifRestart status")
+		reconcileErrorsTotal.Inc()
+		return ctrl.Result{}, err
+	}
+
+	if sendErr != nil {
+		reconcileErrorsTotal.Inc()
+		return ctrl.Result{}, sendErr
+	}
 
-				// Adding log line to output restart information
-				log.Info("Sending restart notification", "pod", pod.Name, "restartCount", status.RestartCount)
+	return ctrl.Result{}, nil
+}
+
+// containerRestart describes a container observed to have crossed
+// Spec.MinRestarts since the last reconcile. key identifies it in the
+// restart-count maps so commitRestart can advance just that entry.
+type containerRestart struct {
+	pod    corev1.Pod
+	status corev1.ContainerStatus
+	key    string
+}
 
-				if err := sendNotification(message, discord, teams, slack); err != nil {
-					log.Error(err, "failed to send notification")
-					return ctrl.Result{}, err
-				}
+// detectRestarts compares each pod's current container restart counts
+// against the in-memory last-seen counts for name, returning the containers
+// whose count increased and meets Spec.MinRestarts. Containers that don't
+// qualify for notification (no delta, or first-ever observation) have their
+// last-seen state advanced immediately, since there's nothing pending for
+// them to retry; containers returned in restarted are left un-advanced
+// until commitRestart confirms their notification was sent.
+func (r *PodNotifRestartReconciler) detectRestarts(name types.NamespacedName, pnr *monitoringv1.PodNotifRestart, pods []corev1.Pod) []containerRestart {
+	r.restartCountsMu.Lock()
+	defer r.restartCountsMu.Unlock()
+
+	if r.restartCounts == nil {
+		r.restartCounts = map[types.NamespacedName]map[string]int32{}
+	}
+	seen, ok := r.restartCounts[name]
+	if !ok {
+		// Seed from status so last-seen counts survive operator restarts.
+		seen = map[string]int32{}
+		for k, v := range pnr.Status.LastRestartCounts {
+			seen[k] = v
+		}
+		r.restartCounts[name] = seen
+	}
+
+	statusCounts := pnr.Status.LastRestartCounts
+	if statusCounts == nil {
+		statusCounts = map[string]int32{}
+	}
+
+	var restarted []containerRestart
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			key := pod.Namespace + "/" + pod.Name + "/" + status.Name
+			last, known := seen[key]
+
+			if known && status.RestartCount > last && status.RestartCount >= pnr.Spec.MinRestarts {
+				restarted = append(restarted, containerRestart{pod: pod, status: status, key: key})
+				continue
 			}
+
+			seen[key] = status.RestartCount
+			statusCounts[key] = status.RestartCount
 		}
 	}
 
-	// Requeue the request to check again in 2 minutes
-	return ctrl.Result{RequeueAfter: time.Minute * 2}, nil
+	pnr.SetLastRestartCounts(statusCounts)
+	return restarted
+}
+
+// commitRestart advances the in-memory and status last-seen restart count
+// for restart's container. Called once its notification has actually been
+// sent, so a failed send leaves the count unadvanced and is detected as a
+// restart again on the next reconcile.
+func (r *PodNotifRestartReconciler) commitRestart(name types.NamespacedName, pnr *monitoringv1.PodNotifRestart, restart containerRestart) {
+	r.restartCountsMu.Lock()
+	if seen, ok := r.restartCounts[name]; ok {
+		seen[restart.key] = restart.status.RestartCount
+	}
+	r.restartCountsMu.Unlock()
+
+	counts := pnr.Status.LastRestartCounts
+	if counts == nil {
+		counts = map[string]int32{}
+	}
+	counts[restart.key] = restart.status.RestartCount
+	pnr.SetLastRestartCounts(counts)
+
+	podRestartsObservedTotal.WithLabelValues(restart.pod.Namespace, restart.pod.Name, restart.status.Name).Inc()
+}
+
+// forgetResource drops any in-memory state tracked for a deleted
+// PodNotifRestart so it doesn't leak across the operator's lifetime.
+func (r *PodNotifRestartReconciler) forgetResource(name types.NamespacedName) {
+	r.rateLimiters.delete(name)
+
+	r.restartCountsMu.Lock()
+	delete(r.restartCounts, name)
+	r.restartCountsMu.Unlock()
+}
+
+// listMonitoredPods lists the pods that fall within pnr's
+// NamespacesToMonitor and PodSelector.
+func (r *PodNotifRestartReconciler) listMonitoredPods(ctx context.Context, pnr *monitoringv1.PodNotifRestart) ([]corev1.Pod, error) {
+	var selector labels.Selector
+	if pnr.Spec.PodSelector != nil {
+		s, err := metav1.LabelSelectorAsSelector(pnr.Spec.PodSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid podSelector: %w", err)
+		}
+		selector = s
+	}
+
+	namespaces := pnr.GetNamespacesToMonitor()
+	if len(namespaces) == 0 {
+		namespaces = []string{""} // empty namespace means "all namespaces" to the client
+	}
+
+	var pods []corev1.Pod
+	for _, ns := range namespaces {
+		var podList corev1.PodList
+		opts := []client.ListOption{client.InNamespace(ns)}
+		if selector != nil {
+			opts = append(opts, client.MatchingLabelsSelector{Selector: selector})
+		}
+		if err := r.Client.List(ctx, &podList, opts...); err != nil {
+			return nil, err
+		}
+		pods = append(pods, podList.Items...)
+	}
+	return pods, nil
 }
 
 // SetupWithManager function
 func (r *PodNotifRestartReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Event{}, eventInvolvedObjectUIDField, func(obj client.Object) []string {
+		event, ok := obj.(*corev1.Event)
+		if !ok || event.InvolvedObject.UID == "" {
+			return nil
+		}
+		return []string{string(event.InvolvedObject.UID)}
+	}); err != nil {
+		return fmt.Errorf("indexing Event.involvedObject.uid: %w", err)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&monitoringv1.PodNotifRestart{}).
-		Owns(&corev1.Pod{}).
+		Watches(
+			&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(r.mapPodToPodNotifRestarts),
+		).
 		Complete(r)
 }
 
-// sendNotification function
-func sendNotification(message string, notifiers ...Notifier) error {
-	var lastError error
-	for _, notifier := range notifiers {
-		if emptyNotifier(notifier) {
+// mapPodToPodNotifRestarts maps a Pod event to reconcile requests for every
+// PodNotifRestart whose NamespacesToMonitor/PodSelector matches it, so
+// restarts are reported as they happen instead of on a fixed poll interval.
+func (r *PodNotifRestartReconciler) mapPodToPodNotifRestarts(ctx context.Context, obj client.Object) []reconcile.Request {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+
+	var list monitoringv1.PodNotifRestartList
+	if err := r.Client.List(ctx, &list); err != nil {
+		r.Log.Error(err, "unable to list PodNotifRestart while mapping pod event")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range list.Items {
+		pnr := &list.Items[i]
+		if !namespaceMonitored(pnr.GetNamespacesToMonitor(), pod.Namespace) {
 			continue
 		}
-		if err := notifier.Notify(message); err != nil {
-			lastError = err
-			fmt.Println("Error sending notification:", err)
+
+		if pnr.Spec.PodSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(pnr.Spec.PodSelector)
+			if err != nil {
+				r.Log.Error(err, "invalid podSelector", "podnotifrestart", pnr.Name)
+				continue
+			}
+			if !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
 		}
-	}
-	return lastError
-}
 
-func emptyNotifier(notifier Notifier) bool {
-	switch n := notifier.(type) {
-	case *DiscordNotifier:
-		return n.WebhookURL == ""
-	case *TeamsNotifier:
-		return n.WebhookURL == ""
-	case *SlackNotifier:
-		return n.WebhookURL == ""
-	default:
-		return true
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: pnr.Namespace, Name: pnr.Name},
+		})
 	}
+	return requests
 }
 
-// postMessage function
-func postMessage(webhookURL string, payload string) error {
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewBufferString(payload))
-	if err != nil {
-		return fmt.Errorf("failed to send post request: %w", err)
+// namespaceMonitored reports whether ns should be monitored given a
+// PodNotifRestart's NamespacesToMonitor list; an empty list monitors every
+// namespace.
+func namespaceMonitored(namespaces []string, ns string) bool {
+	if len(namespaces) == 0 {
+		return true
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("received non-OK HTTP status: %s", resp.Status)
+	for _, n := range namespaces {
+		if n == ns {
+			return true
+		}
 	}
-
-	return nil
+	return false
 }
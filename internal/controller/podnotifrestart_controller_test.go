@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	monitoringv1 "github.com/NoNickeD/pod-restart-notifier-operator-crd/api/v1"
+)
+
+func TestDetectRestartsRetriesUntilCommitted(t *testing.T) {
+	var r PodNotifRestartReconciler
+	name := types.NamespacedName{Namespace: "default", Name: "example"}
+	pnr := &monitoringv1.PodNotifRestart{Spec: monitoringv1.PodNotifRestartSpec{MinRestarts: 1}}
+	pods := []corev1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-0"},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: 1}},
+		},
+	}}
+
+	// First observation: nothing to notify yet, state is advanced immediately.
+	if restarted := r.detectRestarts(name, pnr, pods); len(restarted) != 0 {
+		t.Fatalf("expected no restarts on first observation, got %d", len(restarted))
+	}
+
+	// The container restarts again; this crosses MinRestarts and should be
+	// reported, but not yet committed.
+	pods[0].Status.ContainerStatuses[0].RestartCount = 2
+	restarted := r.detectRestarts(name, pnr, pods)
+	if len(restarted) != 1 {
+		t.Fatalf("expected 1 restart, got %d", len(restarted))
+	}
+
+	// Without a successful send, the same restart must be reported again on
+	// the next reconcile instead of being silently dropped.
+	if again := r.detectRestarts(name, pnr, pods); len(again) != 1 {
+		t.Fatalf("expected the uncommitted restart to be retried, got %d", len(again))
+	}
+
+	// Once committed (simulating a successful send), the restart must not
+	// be reported again for the same RestartCount.
+	r.commitRestart(name, pnr, restarted[0])
+	if settled := r.detectRestarts(name, pnr, pods); len(settled) != 0 {
+		t.Fatalf("expected no restarts after commit, got %d", len(settled))
+	}
+}
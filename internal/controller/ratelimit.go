@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"sync"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+
+	monitoringv1 "github.com/NoNickeD/pod-restart-notifier-operator-crd/api/v1"
+)
+
+// rateLimiterStore holds one token-bucket limiter per PodNotifRestart,
+// so each resource's Spec.NotificationRateLimit is enforced independently.
+type rateLimiterStore struct {
+	mu       sync.Mutex
+	limiters map[types.NamespacedName]*rate.Limiter
+}
+
+// get returns the limiter for name, creating it from spec on first use. It
+// returns nil when spec is nil, meaning notifications for name are not rate
+// limited.
+func (s *rateLimiterStore) get(name types.NamespacedName, spec *monitoringv1.RateLimitSpec) *rate.Limiter {
+	if spec == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.limiters == nil {
+		s.limiters = map[types.NamespacedName]*rate.Limiter{}
+	}
+
+	limiter, ok := s.limiters[name]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(spec.QPS), int(spec.Burst))
+		s.limiters[name] = limiter
+	}
+	return limiter
+}
+
+// delete drops the limiter tracked for name, e.g. once its PodNotifRestart
+// has been deleted.
+func (s *rateLimiterStore) delete(name types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.limiters, name)
+}
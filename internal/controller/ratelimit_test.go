@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	monitoringv1 "github.com/NoNickeD/pod-restart-notifier-operator-crd/api/v1"
+)
+
+func TestRateLimiterStoreGet(t *testing.T) {
+	var store rateLimiterStore
+	name := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	if limiter := store.get(name, nil); limiter != nil {
+		t.Fatalf("expected nil limiter for nil spec, got %v", limiter)
+	}
+
+	spec := &monitoringv1.RateLimitSpec{QPS: 1, Burst: 2}
+	first := store.get(name, spec)
+	if first == nil {
+		t.Fatal("expected a non-nil limiter for a non-nil spec")
+	}
+
+	second := store.get(name, spec)
+	if first != second {
+		t.Error("expected repeated get calls for the same name to return the same limiter instance")
+	}
+
+	other := store.get(types.NamespacedName{Namespace: "default", Name: "other"}, spec)
+	if other == first {
+		t.Error("expected different names to get independent limiter instances")
+	}
+}
+
+func TestRateLimiterStoreDelete(t *testing.T) {
+	var store rateLimiterStore
+	name := types.NamespacedName{Namespace: "default", Name: "example"}
+	spec := &monitoringv1.RateLimitSpec{QPS: 1, Burst: 1}
+
+	first := store.get(name, spec)
+	store.delete(name)
+	second := store.get(name, spec)
+
+	if first == second {
+		t.Error("expected delete to drop the tracked limiter so a later get creates a fresh one")
+	}
+}
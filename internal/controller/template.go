@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1 "github.com/NoNickeD/pod-restart-notifier-operator-crd/api/v1"
+)
+
+// defaultMessageTemplate is rendered when neither Spec.MessageTemplate nor a
+// per-sink override is configured, matching the plain message the operator
+// used to send unconditionally.
+const defaultMessageTemplate = `Pod {{ .Pod.Name }} has restarted {{ .RestartCount }} times`
+
+// defaultAlertMessageTemplate is rendered in place of defaultMessageTemplate
+// when data originates from an Alertmanager alert rather than a detected pod
+// restart (see TemplateData.AlertName), since the restart-count wording
+// doesn't apply there.
+const defaultAlertMessageTemplate = `{{ .AlertSummary }}`
+
+// LastContainerState summarizes a container's last terminated state for
+// template rendering.
+type LastContainerState struct {
+	ExitCode   int32
+	Reason     string
+	Signal     int32
+	FinishedAt metav1.Time
+}
+
+// OwnerInfo describes the controller (Deployment, StatefulSet, ...) that
+// ultimately owns a pod, resolved by walking its OwnerReferences.
+type OwnerInfo struct {
+	Kind string
+	Name string
+}
+
+// String renders o as "Kind/Name", or "" when o is empty.
+func (o OwnerInfo) String() string {
+	if o.Kind == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", o.Kind, o.Name)
+}
+
+// TemplateData is the context exposed to Spec.MessageTemplate and
+// Spec.Templates.* templates, and to the rich per-provider payloads built by
+// RichNotifier implementations.
+type TemplateData struct {
+	Pod          corev1.Pod
+	Container    string
+	RestartCount int32
+	LastState    LastContainerState
+	Node         string
+	Owner        OwnerInfo
+	// RecentLogs holds the trailing lines of the container's previous run,
+	// oldest first.
+	RecentLogs []string
+	// Warnings holds the Reason of the pod's most recent Warning events
+	// (e.g. "BackOff", "OOMKilled"), oldest first.
+	Warnings []string
+	// AlertName and AlertSummary are set instead of the pod-restart fields
+	// above when data originates from an Alertmanager-triggered notification,
+	// letting Spec.MessageTemplate / Spec.Templates.* reference the
+	// underlying alert.
+	AlertName    string
+	AlertSummary string
+}
+
+// RenderMessage executes tmplText against data. When tmplText is empty it
+// uses defaultAlertMessageTemplate if data.AlertName is set, and
+// defaultMessageTemplate otherwise.
+func RenderMessage(tmplText string, data TemplateData) (string, error) {
+	if tmplText == "" {
+		if data.AlertName != "" {
+			tmplText = defaultAlertMessageTemplate
+		} else {
+			tmplText = defaultMessageTemplate
+		}
+	}
+
+	tmpl, err := template.New("message").Parse(tmplText)
+	if err != nil {
+		return "", fmt.Errorf("parsing message template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("executing message template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// templateForSink resolves the effective template text for a bound notifier:
+// its own NotifierSpec.Template override wins, then the matching
+// Spec.Templates.* field for its type, then Spec.MessageTemplate.
+func templateForSink(n BoundNotifier, spec monitoringv1.PodNotifRestartSpec) string {
+	if n.Template != "" {
+		return n.Template
+	}
+
+	if spec.Templates != nil {
+		switch n.Type {
+		case monitoringv1.NotifierTypeDiscord:
+			if spec.Templates.Discord != "" {
+				return spec.Templates.Discord
+			}
+		case monitoringv1.NotifierTypeTeams:
+			if spec.Templates.Teams != "" {
+				return spec.Templates.Teams
+			}
+		case monitoringv1.NotifierTypeSlack:
+			if spec.Templates.Slack != "" {
+				return spec.Templates.Slack
+			}
+		case monitoringv1.NotifierTypeGeneric:
+			if spec.Templates.Generic != "" {
+				return spec.Templates.Generic
+			}
+		}
+	}
+
+	return spec.MessageTemplate
+}
+
+// renderAndSend renders data through each notifier's resolved template and
+// delivers it, using a notifier's rich provider-specific payload when it
+// implements RichNotifier. Each send's duration and result are recorded
+// against pnr_notifier_send_duration_seconds and pnr_notifications_sent_total.
+func renderAndSend(data TemplateData, spec monitoringv1.PodNotifRestartSpec, notifiers []BoundNotifier) error {
+	var lastErr error
+	for _, n := range notifiers {
+		message, err := RenderMessage(templateForSink(n, spec), data)
+		if err != nil {
+			lastErr = err
+			fmt.Println("Error rendering notification:", err)
+			continue
+		}
+
+		provider := string(n.Type)
+		start := time.Now()
+		if rich, ok := n.Notifier.(RichNotifier); ok {
+			err = rich.NotifyRich(message, data)
+		} else {
+			err = n.Notifier.Notify(message)
+		}
+		notifierSendDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+
+		result := "success"
+		if err != nil {
+			result = "error"
+			lastErr = err
+			fmt.Println("Error sending notification:", err)
+		}
+		notificationsSentTotal.WithLabelValues(provider, result).Inc()
+	}
+	return lastErr
+}
+
+// valueOrDash returns s, or "-" when s is empty, for compact rich-payload
+// fields.
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
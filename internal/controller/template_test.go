@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"testing"
+
+	monitoringv1 "github.com/NoNickeD/pod-restart-notifier-operator-crd/api/v1"
+)
+
+func TestTemplateForSinkPrecedence(t *testing.T) {
+	spec := monitoringv1.PodNotifRestartSpec{
+		MessageTemplate: "fallback",
+		Templates: &monitoringv1.TemplatesSpec{
+			Slack: "per-type",
+		},
+	}
+
+	withOverride := BoundNotifier{Type: monitoringv1.NotifierTypeSlack, Template: "per-sink"}
+	if got := templateForSink(withOverride, spec); got != "per-sink" {
+		t.Errorf("NotifierSpec.Template should win, got %q", got)
+	}
+
+	withoutOverride := BoundNotifier{Type: monitoringv1.NotifierTypeSlack}
+	if got := templateForSink(withoutOverride, spec); got != "per-type" {
+		t.Errorf("Spec.Templates.Slack should win over MessageTemplate, got %q", got)
+	}
+
+	unmatchedType := BoundNotifier{Type: monitoringv1.NotifierTypeDiscord}
+	if got := templateForSink(unmatchedType, spec); got != "fallback" {
+		t.Errorf("Spec.MessageTemplate should be used when no per-type template is set, got %q", got)
+	}
+}
+
+func TestRenderMessageDefaultTemplate(t *testing.T) {
+	data := TemplateData{RestartCount: 3}
+	data.Pod.Name = "api-7f9c"
+
+	got, err := RenderMessage("", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "Pod api-7f9c has restarted 3 times"
+	if got != want {
+		t.Errorf("RenderMessage(\"\", data) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package alertmanager implements an HTTP receiver for Prometheus
+// Alertmanager's webhook_config contract, so alerting rules like
+// KubePodCrashLooping can drive the same notifier fan-out the
+// PodNotifRestart reconciler uses, without an extra hop through the watch
+// loop.
+package alertmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controllers "github.com/NoNickeD/pod-restart-notifier-operator-crd/internal/controller"
+)
+
+// Alert is a single firing or resolved alert, as delivered in an
+// Alertmanager webhook payload.
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// WebhookPayload is the body Alertmanager POSTs to a configured webhook
+// receiver. See:
+// https://prometheus.io/docs/alerting/latest/configuration/#webhook_config
+type WebhookPayload struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// Handler implements http.Handler for the Alertmanager webhook receiver
+// contract. For each firing alert it resolves the PodNotifRestart
+// resources monitoring the alert's namespace and fans the alert out to
+// their configured notifiers, the same way the reconciler does for
+// pod-restart events it detects itself.
+type Handler struct {
+	Client client.Client
+	Log    logr.Logger
+}
+
+// ServeHTTP decodes an Alertmanager webhook payload and notifies, once per
+// firing alert, the sinks configured on every PodNotifRestart that monitors
+// the alert's namespace.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var payload WebhookPayload
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("decoding alertmanager payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx := req.Context()
+	for _, alert := range payload.Alerts {
+		if alert.Status != "firing" {
+			continue
+		}
+
+		namespace := alert.Labels["namespace"]
+		targets, err := controllers.NotificationTargetsForNamespace(ctx, h.Client, namespace)
+		if err != nil {
+			h.Log.Error(err, "unable to resolve notifiers for alert", "namespace", namespace, "alertname", alert.Labels["alertname"])
+			continue
+		}
+
+		data := controllers.TemplateData{
+			AlertName:    alert.Labels["alertname"],
+			AlertSummary: formatAlertMessage(alert),
+			Container:    alert.Labels["container"],
+		}
+		data.Pod.Name = alert.Labels["pod"]
+		data.Pod.Namespace = alert.Labels["namespace"]
+
+		for _, target := range targets {
+			if err := controllers.SendAlertNotification(data, target); err != nil {
+				h.Log.Error(err, "failed to send alertmanager-triggered notification", "alertname", alert.Labels["alertname"])
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// formatAlertMessage renders a human-readable message for alert, preferring
+// its summary/description annotations before falling back to its labels.
+func formatAlertMessage(alert Alert) string {
+	if summary := alert.Annotations["summary"]; summary != "" {
+		return summary
+	}
+	if description := alert.Annotations["description"]; description != "" {
+		return description
+	}
+	return fmt.Sprintf("alert %s firing: %v", alert.Labels["alertname"], alert.Labels)
+}
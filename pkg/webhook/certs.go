@@ -0,0 +1,244 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook provisions and rotates the TLS serving certificate used by
+// the operator's admission webhook server, so the operator does not depend
+// on a cert being injected by an external tool (e.g. a Helm pre-install
+// hook) before it can start.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CertManagerInjectCAAnnotation is the annotation cert-manager's CA injector
+// watches on a webhook configuration to inject the CA bundle of the
+// referenced Certificate's issuer.
+const CertManagerInjectCAAnnotation = "cert-manager.io/inject-ca-from"
+
+// certRotationThreshold is how close to expiry a self-signed serving cert
+// may get before EnsureServingCerts replaces it.
+const certRotationThreshold = 30 * 24 * time.Hour
+
+// Options configures how serving certificates are provisioned for the
+// operator's admission webhooks.
+type Options struct {
+	// ServiceName and ServiceNamespace identify the Service fronting the
+	// webhook server, used to build the self-signed cert's DNS SANs.
+	ServiceName      string
+	ServiceNamespace string
+
+	// SecretName is the Secret that stores the self-signed cert/key pair.
+	// Only used when CertManagerCertificateRef is empty.
+	SecretName string
+
+	// CertManagerCertificateRef, if set, names a cert-manager Certificate as
+	// "namespace/name" to annotate onto the webhook configurations instead
+	// of self-signing.
+	CertManagerCertificateRef string
+
+	// ValidatingWebhookName and MutatingWebhookName name the
+	// ValidatingWebhookConfiguration/MutatingWebhookConfiguration to annotate
+	// for cert-manager CA injection. Either may be left empty.
+	ValidatingWebhookName string
+	MutatingWebhookName   string
+}
+
+// EnsureServingCerts provisions TLS serving certs for the operator's
+// admission webhooks. When opts.CertManagerCertificateRef is set it
+// annotates the webhook configurations for cert-manager's CA injector;
+// otherwise it self-signs a cert/key pair into opts.SecretName, creating or
+// rotating it as needed, so the operator no longer relies on an externally
+// injected cert.
+func EnsureServingCerts(ctx context.Context, c client.Client, opts Options) error {
+	if opts.CertManagerCertificateRef != "" {
+		return annotateForCertManager(ctx, c, opts)
+	}
+	return ensureSelfSignedSecret(ctx, c, opts)
+}
+
+func annotateForCertManager(ctx context.Context, c client.Client, opts Options) error {
+	if opts.ValidatingWebhookName != "" {
+		var vwc admissionregistrationv1.ValidatingWebhookConfiguration
+		if err := c.Get(ctx, types.NamespacedName{Name: opts.ValidatingWebhookName}, &vwc); err != nil {
+			return fmt.Errorf("fetching ValidatingWebhookConfiguration %s: %w", opts.ValidatingWebhookName, err)
+		}
+		if vwc.Annotations == nil {
+			vwc.Annotations = map[string]string{}
+		}
+		vwc.Annotations[CertManagerInjectCAAnnotation] = opts.CertManagerCertificateRef
+		if err := c.Update(ctx, &vwc); err != nil {
+			return fmt.Errorf("annotating ValidatingWebhookConfiguration %s: %w", opts.ValidatingWebhookName, err)
+		}
+	}
+
+	if opts.MutatingWebhookName != "" {
+		var mwc admissionregistrationv1.MutatingWebhookConfiguration
+		if err := c.Get(ctx, types.NamespacedName{Name: opts.MutatingWebhookName}, &mwc); err != nil {
+			return fmt.Errorf("fetching MutatingWebhookConfiguration %s: %w", opts.MutatingWebhookName, err)
+		}
+		if mwc.Annotations == nil {
+			mwc.Annotations = map[string]string{}
+		}
+		mwc.Annotations[CertManagerInjectCAAnnotation] = opts.CertManagerCertificateRef
+		if err := c.Update(ctx, &mwc); err != nil {
+			return fmt.Errorf("annotating MutatingWebhookConfiguration %s: %w", opts.MutatingWebhookName, err)
+		}
+	}
+
+	return nil
+}
+
+func ensureSelfSignedSecret(ctx context.Context, c client.Client, opts Options) error {
+	var secret corev1.Secret
+	err := c.Get(ctx, types.NamespacedName{Namespace: opts.ServiceNamespace, Name: opts.SecretName}, &secret)
+	needsCert := true
+	switch {
+	case err == nil:
+		needsCert = certNeedsRotation(secret.Data[corev1.TLSCertKey])
+	case apierrors.IsNotFound(err):
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: opts.SecretName, Namespace: opts.ServiceNamespace},
+			Type:       corev1.SecretTypeTLS,
+		}
+	default:
+		return fmt.Errorf("fetching serving cert secret %s/%s: %w", opts.ServiceNamespace, opts.SecretName, err)
+	}
+
+	if needsCert {
+		certPEM, keyPEM, err := generateSelfSignedCert(opts.ServiceName, opts.ServiceNamespace)
+		if err != nil {
+			return fmt.Errorf("generating self-signed serving cert: %w", err)
+		}
+		secret.Data = map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		}
+
+		if secret.ResourceVersion == "" {
+			if err := c.Create(ctx, &secret); err != nil {
+				return fmt.Errorf("creating serving cert secret %s/%s: %w", opts.ServiceNamespace, opts.SecretName, err)
+			}
+		} else if err := c.Update(ctx, &secret); err != nil {
+			return fmt.Errorf("updating serving cert secret %s/%s: %w", opts.ServiceNamespace, opts.SecretName, err)
+		}
+	}
+
+	// The API server will only trust the generated cert once its CA is
+	// published into the webhook configurations it calls into, so this must
+	// run whether or not the cert was just (re)generated.
+	return patchCABundle(ctx, c, opts, secret.Data[corev1.TLSCertKey])
+}
+
+// patchCABundle writes caBundle onto every webhook entry of
+// opts.ValidatingWebhookName/MutatingWebhookName, mirroring what
+// cert-manager's CA injector does for the CertManagerCertificateRef path.
+func patchCABundle(ctx context.Context, c client.Client, opts Options, caBundle []byte) error {
+	if opts.ValidatingWebhookName != "" {
+		var vwc admissionregistrationv1.ValidatingWebhookConfiguration
+		if err := c.Get(ctx, types.NamespacedName{Name: opts.ValidatingWebhookName}, &vwc); err != nil {
+			return fmt.Errorf("fetching ValidatingWebhookConfiguration %s: %w", opts.ValidatingWebhookName, err)
+		}
+		for i := range vwc.Webhooks {
+			vwc.Webhooks[i].ClientConfig.CABundle = caBundle
+		}
+		if err := c.Update(ctx, &vwc); err != nil {
+			return fmt.Errorf("patching ValidatingWebhookConfiguration %s caBundle: %w", opts.ValidatingWebhookName, err)
+		}
+	}
+
+	if opts.MutatingWebhookName != "" {
+		var mwc admissionregistrationv1.MutatingWebhookConfiguration
+		if err := c.Get(ctx, types.NamespacedName{Name: opts.MutatingWebhookName}, &mwc); err != nil {
+			return fmt.Errorf("fetching MutatingWebhookConfiguration %s: %w", opts.MutatingWebhookName, err)
+		}
+		for i := range mwc.Webhooks {
+			mwc.Webhooks[i].ClientConfig.CABundle = caBundle
+		}
+		if err := c.Update(ctx, &mwc); err != nil {
+			return fmt.Errorf("patching MutatingWebhookConfiguration %s caBundle: %w", opts.MutatingWebhookName, err)
+		}
+	}
+
+	return nil
+}
+
+func certNeedsRotation(certPEM []byte) bool {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return true
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+	return time.Until(cert.NotAfter) < certRotationThreshold
+}
+
+func generateSelfSignedCert(serviceName, serviceNamespace string) (certPEM, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dnsNames := []string{
+		serviceName,
+		fmt.Sprintf("%s.%s", serviceName, serviceNamespace),
+		fmt.Sprintf("%s.%s.svc", serviceName, serviceNamespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", serviceName, serviceNamespace),
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: dnsNames[len(dnsNames)-1]},
+		DNSNames:              dnsNames,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM, nil
+}